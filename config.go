@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/labstack/gommon/color"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigFormat is the format of a config file backing flag defaults.
+type ConfigFormat int32
+
+const (
+	// ConfigFormatAuto detects the format from the file extension.
+	ConfigFormatAuto ConfigFormat = iota
+	// ConfigFormatJSON parses the config file as JSON.
+	ConfigFormatJSON
+	// ConfigFormatYAML parses the config file as YAML.
+	ConfigFormatYAML
+	// ConfigFormatTOML parses the config file as TOML.
+	ConfigFormatTOML
+	// ConfigFormatEnv parses the config file as a dotenv file (KEY=VALUE per line).
+	ConfigFormatEnv
+)
+
+// Config describes a layered configuration source that's consulted for flag
+// defaults before CLI parsing happens. Precedence, from highest to lowest, is:
+// explicit CLI arg > env var ($ENV expansion in the tag's default) > Config
+// file > tag default.
+type Config struct {
+	// Path is the config file path. Empty disables config-file backing.
+	Path string
+	// Format selects the parser. Defaults to ConfigFormatAuto, which sniffs
+	// Path's extension (.json, .yaml/.yml, .toml, .env).
+	Format ConfigFormat
+	// Required, when true, turns a missing/unreadable file into an error
+	// instead of a silent no-op.
+	Required bool
+}
+
+// activeConfig is the config-file backing installed by SetConfig. It's
+// package-wide, construction-time state, the same shape as defaultStyle,
+// usageFormatter and defaultPrompter: callers call SetConfig themselves
+// before Run/RunCommand builds a command's flagSet (Run/RunCommand take no
+// Config and never call SetConfig), and flag.init consults it while applying
+// tag defaults.
+var activeConfig *Config
+
+// SetConfig installs cfg as the config-file backing for flag defaults. Call
+// it before building the flagSet for a command (i.e. before Run/RunCommand
+// parses its args) so flag.init can consult it while resolving defaults.
+// Pass nil to disable config-file backing again.
+func SetConfig(cfg *Config) {
+	activeConfig = cfg
+	configValuesCache = nil
+}
+
+// GetConfig returns the config-file backing installed by SetConfig, or nil.
+func GetConfig() *Config {
+	return activeConfig
+}
+
+// configValuesCache memoizes loadConfigValues's parse of activeConfig, keyed
+// by the *Config pointer it was parsed from, so a command with several
+// cfg-tagged fields reads and decodes the file once per command instead of
+// once per flag. SetConfig resets it whenever activeConfig is replaced.
+var configValuesCache *configValuesCacheEntry
+
+type configValuesCacheEntry struct {
+	cfg    *Config
+	values map[string][]string
+	err    error
+}
+
+func cachedConfigValues(cfg *Config) (map[string][]string, error) {
+	if configValuesCache != nil && configValuesCache.cfg == cfg {
+		return configValuesCache.values, configValuesCache.err
+	}
+	values, err := loadConfigValues(cfg)
+	configValuesCache = &configValuesCacheEntry{cfg: cfg, values: values, err: err}
+	return values, err
+}
+
+func detectConfigFormat(path string) ConfigFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ConfigFormatJSON
+	case ".yaml", ".yml":
+		return ConfigFormatYAML
+	case ".toml":
+		return ConfigFormatTOML
+	case ".env":
+		return ConfigFormatEnv
+	}
+	return ConfigFormatJSON
+}
+
+// loadConfigValues reads cfg.Path and flattens it into dotted keys, e.g.
+// {"server":{"addr":":8080"}} becomes {"server.addr": [":8080"]}. A key maps
+// to more than one value when the source is a list, e.g. {"tags":["a","b"]}
+// becomes {"tags": ["a", "b"]} so a []string flag sees each element in turn,
+// the same way repeated "-f a -f b" CLI args do.
+func loadConfigValues(cfg *Config) (map[string][]string, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		if cfg.Required {
+			return nil, fmt.Errorf("read config %s: %v", cfg.Path, err)
+		}
+		return nil, nil
+	}
+
+	format := cfg.Format
+	if format == ConfigFormatAuto {
+		format = detectConfigFormat(cfg.Path)
+	}
+
+	if format == ConfigFormatEnv {
+		return parseEnvFile(data), nil
+	}
+
+	var raw map[string]interface{}
+	switch format {
+	case ConfigFormatJSON:
+		err = json.Unmarshal(data, &raw)
+	case ConfigFormatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case ConfigFormatTOML:
+		_, err = toml.Decode(string(data), &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %v", cfg.Path, err)
+	}
+
+	values := make(map[string][]string)
+	flattenConfig("", raw, values)
+	return values, nil
+}
+
+func flattenConfig(prefix string, raw map[string]interface{}, out map[string][]string) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenConfig(key, val, out)
+		case map[interface{}]interface{}:
+			nested := make(map[string]interface{}, len(val))
+			for nk, nv := range val {
+				nested[fmt.Sprintf("%v", nk)] = nv
+			}
+			flattenConfig(key, nested, out)
+		case []interface{}:
+			for _, item := range val {
+				out[key] = append(out[key], fmt.Sprintf("%v", item))
+			}
+		default:
+			out[key] = append(out[key], fmt.Sprintf("%v", val))
+		}
+	}
+}
+
+func parseEnvFile(data []byte) map[string][]string {
+	values := make(map[string][]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		index := strings.Index(line, "=")
+		if index == -1 {
+			continue
+		}
+		key := strings.TrimSpace(line[:index])
+		val := strings.Trim(strings.TrimSpace(line[index+1:]), `"'`)
+		values[key] = append(values[key], val)
+	}
+	return values
+}
+
+// applyConfigValue resolves a Config-file value onto fl, for a field
+// carrying a `cfg:"dotted.key"` tag, and is called from flag.init before the
+// tag default is applied — so "config file > tag default" actually holds,
+// rather than being checked too late against a default that's already won.
+// A tag default that itself does $ENV expansion (e.g. `default:"$PORT"`)
+// outranks the config file per the documented "env var > Config file"
+// precedence, so applyConfigValue defers to it instead of overriding it.
+// It reuses setWithProperType and fl.resolveSecretArg so slice/map/Decoder
+// and secret-tag handling stay uniform with normal flag parsing. It reports
+// whether a config value was applied.
+func applyConfigValue(fl *flag, clr color.Color) (bool, error) {
+	if activeConfig == nil {
+		return false, nil
+	}
+	if strings.Contains(fl.tag.defaultValue, "$") {
+		// Look only at the env vars the default names, not at its whole
+		// expansion: parseExpression's numeric fallback substitutes "0" for
+		// an unset env var, and literal text around a $VAR expands to a
+		// non-empty string even when the var is unset. Either would make an
+		// unset $PORT look "set" and wrongly outrank the config file, so
+		// only an actually-set env var wins.
+		envSet := false
+		os.Expand(fl.tag.defaultValue, func(name string) string {
+			if os.Getenv(name) != "" {
+				envSet = true
+			}
+			return ""
+		})
+		if envSet {
+			return false, nil
+		}
+	}
+	key := fl.field.Tag.Get("cfg")
+	if key == "" {
+		return false, nil
+	}
+	values, err := cachedConfigValues(activeConfig)
+	if err != nil {
+		return false, err
+	}
+	items, ok := values[key]
+	if !ok || len(items) == 0 {
+		return false, nil
+	}
+	for _, s := range items {
+		resolved, err := fl.resolveSecretArg(s, clr, true)
+		if err != nil {
+			return false, fmt.Errorf("config key %s: %v", key, err)
+		}
+		if err := setWithProperType(fl, fl.field.Type, fl.value, resolved, clr, false); err != nil {
+			return false, fmt.Errorf("config key %s: %v", key, err)
+		}
+	}
+	fl.isAssigned = true
+	return true, nil
+}
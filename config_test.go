@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/gommon/color"
+)
+
+// newCfgFlag builds a *flag over an int field tagged cfg:"server.port" and
+// default:"$PORT", the shape applyConfigValue is documented to resolve
+// "config file > tag default" for: an unset $PORT should let the config
+// file value through; a set $PORT should win per the documented
+// "env var > Config file" precedence.
+func newCfgFlag(t *testing.T) *flag {
+	t.Helper()
+	type target struct {
+		Port int `cli:"port" cfg:"server.port" default:"$PORT"`
+	}
+	v := reflect.ValueOf(&target{}).Elem()
+	field := v.Type().Field(0)
+	fl := &flag{field: field, value: v.Field(0)}
+	// applyConfigValue only consults fl.tag.defaultValue, which real flags
+	// get from newFlag's tag parsing; set it directly here so the $PORT
+	// precedence check actually exercises the guard under test.
+	fl.tag.defaultValue = "$PORT"
+	return fl
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestApplyConfigValueUsesConfigWhenEnvVarUnset(t *testing.T) {
+	os.Unsetenv("PORT")
+	path := writeConfigFile(t, `{"server":{"port":9090}}`)
+	SetConfig(&Config{Path: path, Format: ConfigFormatJSON})
+	defer SetConfig(nil)
+
+	fl := newCfgFlag(t)
+	applied, err := applyConfigValue(fl, color.Color{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !applied {
+		t.Fatalf("expected the config file value to be applied when $PORT is unset")
+	}
+	if got := fl.value.Interface().(int); got != 9090 {
+		t.Fatalf("got %d, want 9090 (config file value, since $PORT is unset)", got)
+	}
+}
+
+func TestApplyConfigValueDefersToSetEnvVar(t *testing.T) {
+	os.Setenv("PORT", "1234")
+	defer os.Unsetenv("PORT")
+	path := writeConfigFile(t, `{"server":{"port":9090}}`)
+	SetConfig(&Config{Path: path, Format: ConfigFormatJSON})
+	defer SetConfig(nil)
+
+	fl := newCfgFlag(t)
+	applied, err := applyConfigValue(fl, color.Color{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied {
+		t.Fatalf("expected applyConfigValue to defer to the set $PORT env var, not the config file")
+	}
+}
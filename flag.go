@@ -11,8 +11,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/Bowery/prompt"
 	"github.com/labstack/gommon/color"
 	"github.com/mkideal/pkg/expr"
 )
@@ -38,43 +38,139 @@ func newFlagSet() *flagSet {
 }
 
 func (fs *flagSet) readPrompt(w io.Writer, clr color.Color) {
+	p := defaultPrompter
 	for _, fl := range fs.flags {
 		if fl.isAssigned || fl.tag.prompt == "" {
 			continue
 		}
-		// read ...
 		prefix := fl.tag.prompt + ": "
-		var (
-			data string
-			yes  bool
-		)
-		if fl.tag.isPassword {
-			data, fs.err = prompt.Password(prefix)
-			if fs.err == nil && data != "" {
-				fl.set(data, data, clr)
-			}
-		} else if fl.isBoolean() {
-			yes, fs.err = prompt.Ask(prefix)
-			if fs.err == nil {
-				fl.value.SetBool(yes)
-			}
-		} else if fl.tag.defaultValue != "" {
-			data, fs.err = prompt.BasicDefault(prefix, fl.tag.defaultValue)
-			if fs.err == nil {
-				fl.set(data, data, clr)
-			}
-		} else {
-			data, fs.err = prompt.Basic(prefix, fl.tag.required)
-			if fs.err == nil {
-				fl.set(data, data, clr)
+		choices := fl.choices()
+		retry := fl.retry()
+
+		var err error
+		for attempt := 0; attempt < retry; attempt++ {
+			err = fs.readPromptOnce(p, fl, prefix, choices, clr)
+			if err == nil {
+				break
 			}
+			fmt.Fprintf(w, "%s\n", clr.Red(err.Error()))
 		}
-		if fs.err != nil {
+		if err != nil {
+			fs.err = err
 			return
 		}
 	}
 }
 
+// readPromptOnce performs a single read-and-assign attempt for fl, honoring
+// choices (single/multi select), confirm (yes/no with a default), and
+// validate (re-prompt on mismatch, handled by the caller's retry loop).
+func (fs *flagSet) readPromptOnce(p Prompter, fl *flag, prefix string, choices []string, clr color.Color) error {
+	var data string
+	var err error
+
+	switch {
+	case fl.tag.isPassword:
+		data, err = p.Password(prefix, fl.mask())
+	case len(choices) > 0 && fl.field.Type.Kind() == reflect.Slice:
+		var selected []string
+		selected, err = p.MultiSelect(prefix, choices)
+		if err == nil {
+			// Validate the whole selection before touching fl.value: if a
+			// later item fails, an earlier item must not be left appended,
+			// or a retried attempt would mix stale entries into the final
+			// slice (see flag.go's retry loop above).
+			for _, s := range selected {
+				if verr := validateTag(fl.validate(), s); verr != nil {
+					return verr
+				}
+			}
+			for _, s := range selected {
+				if serr := setWithProperType(fl, fl.field.Type, fl.value, s, clr, false); serr != nil {
+					return serr
+				}
+			}
+			fl.isAssigned, fl.isSet = true, true
+			return nil
+		}
+	case len(choices) > 0:
+		data, err = p.Select(prefix, choices)
+	case fl.confirm() && fl.isBoolean():
+		var yes bool
+		yes, err = p.Confirm(prefix, fl.getBool())
+		if err == nil {
+			fl.value.SetBool(yes)
+			fl.isAssigned, fl.isSet = true, true
+			return nil
+		}
+	case fl.isBoolean():
+		var yes bool
+		yes, err = p.Confirm(prefix, false)
+		if err == nil {
+			fl.value.SetBool(yes)
+			fl.isAssigned, fl.isSet = true, true
+			return nil
+		}
+	case fl.tag.defaultValue != "":
+		data, err = p.BasicDefault(prefix, fl.tag.defaultValue)
+	default:
+		data, err = p.Basic(prefix, fl.tag.required)
+	}
+	if err != nil {
+		return err
+	}
+	if verr := validateTag(fl.validate(), data); verr != nil {
+		return verr
+	}
+	return fl.setFromPrompt(data, clr)
+}
+
+// choices parses the `choices:"a|b|c"` struct tag, if present.
+func (fl *flag) choices() []string {
+	raw := fl.field.Tag.Get("choices")
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, "|")
+}
+
+// validate returns the raw `validate:"..."` struct tag, if present.
+func (fl *flag) validate() string {
+	return fl.field.Tag.Get("validate")
+}
+
+// confirm reports whether the field carries `confirm:"true"`.
+func (fl *flag) confirm() bool {
+	return fl.field.Tag.Get("confirm") == "true"
+}
+
+// retry returns the `retry:"N"` struct tag as an attempt count, defaulting
+// to 1 (no re-ask) when absent or malformed.
+func (fl *flag) retry() int {
+	raw := fl.field.Tag.Get("retry")
+	if raw == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// mask returns the rune from a `mask:"*"` struct tag that the Prompter
+// should echo in place of each typed character, or 0 if the field doesn't
+// carry one (meaning the Prompter's default no-echo password behavior
+// applies).
+func (fl *flag) mask() rune {
+	raw := fl.field.Tag.Get("mask")
+	if raw == "" {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(raw)
+	return r
+}
+
 type flag struct {
 	field reflect.StructField
 	value reflect.Value
@@ -114,6 +210,16 @@ func newFlag(field reflect.StructField, value reflect.Value, tag *tagProperty, c
 }
 
 func (fl *flag) init(clr color.Color, dontSetValue bool) error {
+	if !dontSetValue {
+		applied, err := applyConfigValue(fl, clr)
+		if err != nil {
+			return err
+		}
+		if applied {
+			return nil
+		}
+	}
+
 	isNumber := fl.isInteger() || fl.isFloat()
 	dft, err := parseExpression(fl.tag.defaultValue, isNumber)
 	if err != nil {
@@ -253,6 +359,13 @@ func (fl *flag) getBool() bool {
 
 func (fl *flag) setDefault(s string, clr color.Color) error {
 	fl.isAssigned = true
+	if s != "" {
+		resolved, err := fl.resolveSecretArg(s, clr, false)
+		if err != nil {
+			return err
+		}
+		s = resolved
+	}
 	if fl.isNeedDelaySet {
 		fl.lastValue = s
 		return nil
@@ -264,6 +377,13 @@ func (fl *flag) set(actualFlagName, s string, clr color.Color) error {
 	fl.isSet = true
 	fl.isAssigned = true
 	fl.actualFlagName = actualFlagName
+	if s != "" {
+		resolved, err := fl.resolveSecretArg(s, clr, true)
+		if err != nil {
+			return err
+		}
+		s = resolved
+	}
 	if fl.isNeedDelaySet {
 		fl.lastValue = s
 		return nil
@@ -287,7 +407,7 @@ func setWithProperType(fl *flag, typ reflect.Type, val reflect.Value, s string,
 		if v, err := getBool(s, clr); err == nil {
 			val.SetBool(v)
 		} else {
-			return err
+			return redactSecretErr(fl, s, err)
 		}
 
 	case reflect.String:
@@ -301,7 +421,7 @@ func setWithProperType(fl *flag, typ reflect.Type, val reflect.Value, s string,
 				return errors.New(clr.Red("value overflow"))
 			}
 		} else {
-			return err
+			return redactSecretErr(fl, s, err)
 		}
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
@@ -312,7 +432,7 @@ func setWithProperType(fl *flag, typ reflect.Type, val reflect.Value, s string,
 				return errors.New(clr.Red("value overflow"))
 			}
 		} else {
-			return err
+			return redactSecretErr(fl, s, err)
 		}
 
 	case reflect.Float32, reflect.Float64:
@@ -323,7 +443,7 @@ func setWithProperType(fl *flag, typ reflect.Type, val reflect.Value, s string,
 				return errors.New(clr.Red("value overflow"))
 			}
 		} else {
-			return err
+			return redactSecretErr(fl, s, err)
 		}
 
 	case reflect.Slice:
@@ -492,131 +612,23 @@ func GetUsageStyle() UsageStyle {
 	return defaultStyle
 }
 
-// SetUsageStyle sets default style
+// SetUsageStyle sets default style. It's a thin shim over SetUsageFormatter
+// that picks one of the two built-in templates; call SetUsageFormatter
+// directly for anything beyond NormalStyle/ManualStyle.
 func SetUsageStyle(style UsageStyle) {
 	defaultStyle = style
+	usageFormatter = newTemplateFormatter(templateForStyle(style))
 }
 
 type flagSlice []*flag
 
+// String renders fs using the currently registered UsageFormatter.
 func (fs flagSlice) String(clr color.Color) string {
-	var (
-		lenShort                 = 0
-		lenLong                  = 0
-		lenNameAndDefaultAndLong = 0
-		lenSep                   = len(sepName)
-		sepSpaces                = strings.Repeat(" ", lenSep)
-	)
-	for _, fl := range fs {
-		tag := fl.tag
-		l := 0
-		for _, shortName := range tag.shortNames {
-			l += len(shortName) + lenSep
-		}
-		if l > lenShort {
-			lenShort = l
-		}
-		l = 0
-		for _, longName := range tag.longNames {
-			l += len(longName) + lenSep
-		}
-		if l > lenLong {
-			lenLong = l
-		}
-		lenDft := 0
-		if tag.defaultValue != "" {
-			lenDft = len(tag.defaultValue) + 3 // 3=len("[=]")
-		}
-		l += lenDft
-		if tag.name != "" {
-			l += len(tag.name) + 1 // 1=len("=")
-		}
-		if l > lenNameAndDefaultAndLong {
-			lenNameAndDefaultAndLong = l
-		}
-	}
-
-	buff := bytes.NewBufferString("")
-	for _, fl := range fs {
-		var (
-			tag         = fl.tag
-			shortStr    = strings.Join(tag.shortNames, sepName)
-			longStr     = strings.Join(tag.longNames, sepName)
-			format      = ""
-			defaultStr  = ""
-			nameStr     = ""
-			usagePrefix = " "
-		)
-		if tag.defaultValue != "" {
-			defaultStr = fmt.Sprintf("[=%s]", tag.defaultValue)
-		}
-		if tag.name != "" {
-			nameStr = "=" + tag.name
-		}
-		if tag.required {
-			usagePrefix = clr.Red("*")
-		}
-		usage := usagePrefix + tag.usage
-
-		spaceSize := lenSep + lenNameAndDefaultAndLong
-		spaceSize -= len(nameStr) + len(defaultStr) + len(longStr)
-
-		if defaultStr != "" {
-			defaultStr = clr.Grey(defaultStr)
-		}
-		if nameStr != "" {
-			nameStr = "=" + clr.Bold(tag.name)
-		}
-
-		if longStr == "" {
-			format = fmt.Sprintf("%%%ds%%s%s%%s", lenShort, sepSpaces)
-			fillStr := fillSpaces(nameStr+defaultStr, spaceSize)
-			fmt.Fprintf(buff, format+"\n", shortStr, fillStr, usage)
-		} else {
-			if shortStr == "" {
-				format = fmt.Sprintf("%%%ds%%s%%s", lenShort+lenSep)
-			} else {
-				format = fmt.Sprintf("%%%ds%s%%s%%s", lenShort, sepName)
-			}
-			fillStr := fillSpaces(longStr+nameStr+defaultStr, spaceSize)
-			fmt.Fprintf(buff, format+"\n", shortStr, fillStr, usage)
-		}
-	}
-	return buff.String()
-}
-
-func fillSpaces(s string, spaceSize int) string {
-	return s + strings.Repeat(" ", spaceSize)
+	return usageFormatter.FormatFlags(fs, clr)
 }
 
+// StringWithStyle renders fs with a one-off style, without touching the
+// package-wide formatter set by SetUsageStyle/SetUsageFormatter.
 func (fs flagSlice) StringWithStyle(clr color.Color, style UsageStyle) string {
-	if style != ManualStyle {
-		return fs.String(clr)
-	}
-
-	buf := bytes.NewBufferString("")
-	linePrefix := "  "
-	for i, fl := range fs {
-		if i != 0 {
-			buf.WriteString("\n")
-		}
-		names := strings.Join(append(fl.tag.shortNames, fl.tag.longNames...), sepName)
-		buf.WriteString(linePrefix)
-		buf.WriteString(clr.Bold(names))
-		if fl.tag.name != "" {
-			buf.WriteString("=" + clr.Bold(fl.tag.name))
-		}
-		if fl.tag.defaultValue != "" {
-			buf.WriteString(clr.Grey(fmt.Sprintf("[=%s]", fl.tag.defaultValue)))
-		}
-		buf.WriteString("\n")
-		buf.WriteString(linePrefix)
-		buf.WriteString("    ")
-		if fl.tag.required {
-			buf.WriteString(clr.Red("*"))
-		}
-		buf.WriteString(fl.tag.usage)
-		buf.WriteString("\n")
-	}
-	return buf.String()
+	return newTemplateFormatter(templateForStyle(style)).FormatFlags(fs, clr)
 }
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/gommon/color"
+)
+
+// fakeMultiSelectPrompter returns the next []string in results on each
+// MultiSelect call, so a test can simulate a first attempt that needs a
+// retry followed by a clean second attempt. Every other Prompter method
+// panics: readPromptOnce's multi-select branch shouldn't reach them.
+type fakeMultiSelectPrompter struct {
+	results [][]string
+	calls   int
+}
+
+func (f *fakeMultiSelectPrompter) Password(prefix string, mask rune) (string, error) {
+	panic("unexpected Password call")
+}
+func (f *fakeMultiSelectPrompter) Basic(prefix string, required bool) (string, error) {
+	panic("unexpected Basic call")
+}
+func (f *fakeMultiSelectPrompter) BasicDefault(prefix, dft string) (string, error) {
+	panic("unexpected BasicDefault call")
+}
+func (f *fakeMultiSelectPrompter) Confirm(prefix string, dft bool) (bool, error) {
+	panic("unexpected Confirm call")
+}
+func (f *fakeMultiSelectPrompter) Select(prefix string, choices []string) (string, error) {
+	panic("unexpected Select call")
+}
+func (f *fakeMultiSelectPrompter) MultiSelect(prefix string, choices []string) ([]string, error) {
+	r := f.results[f.calls]
+	f.calls++
+	return r, nil
+}
+
+// newMultiSelectFlag builds a *flag over a []string field tagged with
+// choices and a validate rule rejecting "bad", the same shape newFlag
+// builds from a real struct field (tag properties left at their zero value).
+func newMultiSelectFlag(t *testing.T) *flag {
+	t.Helper()
+	type target struct {
+		Tags []string `cli:"tags" choices:"dev|staging|bad" validate:"regexp:^(dev|staging)$" retry:"2"`
+	}
+	v := reflect.ValueOf(&target{}).Elem()
+	field := v.Type().Field(0)
+	fl := &flag{field: field, value: v.Field(0)}
+	return fl
+}
+
+// TestReadPromptOnceMultiSelectRollsBackOnValidationFailure guards against a
+// retried multi-select attempt leaking entries from an earlier, failed
+// attempt into the field's final slice (see readPromptOnce's multi-select
+// branch in flag.go).
+func TestReadPromptOnceMultiSelectRollsBackOnValidationFailure(t *testing.T) {
+	fl := newMultiSelectFlag(t)
+	fs := &flagSet{flagMap: make(map[string]*flag), flags: []*flag{fl}}
+
+	p := &fakeMultiSelectPrompter{
+		results: [][]string{
+			{"dev", "bad"}, // first attempt: "bad" fails validate, must not leave "dev" appended
+			{"staging"},    // second (retried) attempt: clean
+		},
+	}
+
+	err := fs.readPromptOnce(p, fl, "tags: ", fl.choices(), color.Color{})
+	if err == nil {
+		t.Fatalf("expected the first attempt to fail validation")
+	}
+	if got := fl.value.Interface().([]string); len(got) != 0 {
+		t.Fatalf("first failed attempt must not append anything, got %v", got)
+	}
+
+	err = fs.readPromptOnce(p, fl, "tags: ", fl.choices(), color.Color{})
+	if err != nil {
+		t.Fatalf("second attempt: unexpected error: %v", err)
+	}
+	want := []string{"staging"}
+	if got := fl.value.Interface().([]string); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v (stale entry from the failed attempt leaked in)", got, want)
+	}
+}
+
+func TestReadPromptRetriesMultiSelectAfterValidationFailure(t *testing.T) {
+	fl := newMultiSelectFlag(t)
+	fl.tag.prompt = "Pick tags"
+	fs := &flagSet{flagMap: make(map[string]*flag), flags: []*flag{fl}}
+
+	p := &fakeMultiSelectPrompter{
+		results: [][]string{
+			{"dev", "bad"},
+			{"staging"},
+		},
+	}
+	prevPrompter := defaultPrompter
+	SetPrompter(p)
+	defer SetPrompter(prevPrompter)
+
+	var buf bytes.Buffer
+	fs.readPrompt(&buf, color.Color{})
+
+	if fs.err != nil {
+		t.Fatalf("unexpected flagSet error: %v", fs.err)
+	}
+	want := []string{"staging"}
+	if got := fl.value.Interface().([]string); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bowery/prompt"
+	"github.com/labstack/gommon/color"
+	"golang.org/x/term"
+)
+
+// Prompter is the interactive-input surface readPrompt drives. The default
+// implementation talks to a real TTY; tests and non-interactive callers can
+// install their own via SetPrompter.
+type Prompter interface {
+	// Password reads a value without echoing keystrokes, unless mask is
+	// non-zero, in which case it's echoed once per typed character instead.
+	Password(prefix string, mask rune) (string, error)
+	// Basic reads a line, re-asking while required is true and the line is empty.
+	Basic(prefix string, required bool) (string, error)
+	// BasicDefault reads a line, returning dft for an empty line.
+	BasicDefault(prefix, dft string) (string, error)
+	// Confirm asks a yes/no question, returning dft when the line is empty.
+	Confirm(prefix string, dft bool) (bool, error)
+	// Select lets the user pick a single value out of choices.
+	Select(prefix string, choices []string) (string, error)
+	// MultiSelect lets the user toggle any number of choices.
+	MultiSelect(prefix string, choices []string) ([]string, error)
+}
+
+var defaultPrompter Prompter = &ansiPrompter{}
+
+// SetPrompter installs p as the Prompter readPrompt uses. Pass nil to restore
+// the built-in ANSI terminal implementation. Useful for driving prompts from
+// tests or other non-TTY environments.
+func SetPrompter(p Prompter) {
+	if p == nil {
+		p = &ansiPrompter{}
+	}
+	defaultPrompter = p
+}
+
+// GetPrompter returns the Prompter currently in use.
+func GetPrompter() Prompter {
+	return defaultPrompter
+}
+
+// ansiPrompter is the default Prompter: Password/Basic/BasicDefault delegate
+// to github.com/Bowery/prompt as before, Select/MultiSelect/Confirm render an
+// ANSI arrow-key/space-bar list on top of a raw terminal.
+type ansiPrompter struct{}
+
+func (ansiPrompter) Password(prefix string, mask rune) (string, error) {
+	if mask == 0 {
+		return prompt.Password(prefix)
+	}
+	return readMaskedPassword(prefix, mask)
+}
+
+// readMaskedPassword reads a password from a raw terminal, echoing mask in
+// place of each typed character. github.com/Bowery/prompt's Password has no
+// such option (it doesn't echo anything), so a `mask:"*"` field is read here
+// directly instead of delegating, the same way runArrowList reads its own
+// raw-mode loop rather than going through that library.
+func readMaskedPassword(prefix string, mask rune) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return prompt.Password(prefix)
+	}
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return prompt.Password(prefix)
+	}
+	defer term.Restore(fd, old)
+
+	fmt.Fprint(os.Stdout, prefix)
+	var runes []rune
+	buf := make([]byte, 4)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case n == 1 && (buf[0] == '\r' || buf[0] == '\n'):
+			fmt.Fprint(os.Stdout, "\r\n")
+			return string(runes), nil
+		case n == 1 && (buf[0] == 127 || buf[0] == 8):
+			if len(runes) > 0 {
+				runes = runes[:len(runes)-1]
+				fmt.Fprint(os.Stdout, "\b \b")
+			}
+		case n == 1 && buf[0] == 3: // Ctrl-C
+			fmt.Fprint(os.Stdout, "\r\n")
+			return "", fmt.Errorf("interrupted")
+		default:
+			r, _ := utf8.DecodeRune(buf[:n])
+			if r != utf8.RuneError {
+				runes = append(runes, r)
+				fmt.Fprintf(os.Stdout, "%c", mask)
+			}
+		}
+	}
+}
+
+func (ansiPrompter) Basic(prefix string, required bool) (string, error) {
+	return prompt.Basic(prefix, required)
+}
+
+func (ansiPrompter) BasicDefault(prefix, dft string) (string, error) {
+	return prompt.BasicDefault(prefix, dft)
+}
+
+func (ansiPrompter) Confirm(prefix string, dft bool) (bool, error) {
+	suffix := "[Y/n]"
+	if !dft {
+		suffix = "[y/N]"
+	}
+	line, err := prompt.Basic(prefix+" "+suffix+" ", false)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(line) == "" {
+		return dft, nil
+	}
+	return getBool(line, color.Color{})
+}
+
+// Select renders choices as a navigable list: up/down arrows move the
+// cursor, enter confirms. Falls back to plain numbered input when stdin
+// isn't a terminal, so it keeps working over pipes/CI.
+func (ansiPrompter) Select(prefix string, choices []string) (string, error) {
+	idx, err := runArrowList(prefix, choices, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(idx) == 0 {
+		return "", fmt.Errorf("no selection made")
+	}
+	return choices[idx[0]], nil
+}
+
+// MultiSelect is like Select but the space bar toggles the highlighted entry
+// and any number of entries may be checked before enter confirms.
+func (ansiPrompter) MultiSelect(prefix string, choices []string) ([]string, error) {
+	idx, err := runArrowList(prefix, choices, make(map[int]bool))
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]string, len(idx))
+	for i, j := range idx {
+		selected[i] = choices[j]
+	}
+	return selected, nil
+}
+
+// runArrowList drives the raw-mode list UI shared by Select/MultiSelect.
+// checked == nil means single-select (space has no effect, enter picks the
+// cursor row); non-nil means multi-select (space toggles, enter submits the
+// checked set).
+func runArrowList(prefix string, choices []string, checked map[int]bool) ([]int, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return runListFallback(prefix, choices, checked)
+	}
+
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return runListFallback(prefix, choices, checked)
+	}
+	defer term.Restore(fd, old)
+
+	cursor := 0
+	redraw := func() {
+		fmt.Fprintf(os.Stdout, "\r\n%s\r\n", prefix)
+		for i, choice := range choices {
+			mark := "  "
+			if cursor == i {
+				mark = "> "
+			}
+			box := ""
+			if checked != nil {
+				if checked[i] {
+					box = "[x] "
+				} else {
+					box = "[ ] "
+				}
+			}
+			fmt.Fprintf(os.Stdout, "%s%s%s\r\n", mark, box, choice)
+		}
+	}
+	redraw()
+
+	buf := make([]byte, 3)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n == 1 && buf[0] == '\r':
+			if checked == nil {
+				return []int{cursor}, nil
+			}
+			result := make([]int, 0, len(checked))
+			for i := range choices {
+				if checked[i] {
+					result = append(result, i)
+				}
+			}
+			return result, nil
+		case n == 1 && buf[0] == ' ' && checked != nil:
+			checked[cursor] = !checked[cursor]
+		case n == 3 && buf[0] == 27 && buf[1] == '[' && buf[2] == 'A':
+			if cursor > 0 {
+				cursor--
+			}
+		case n == 3 && buf[0] == 27 && buf[1] == '[' && buf[2] == 'B':
+			if cursor < len(choices)-1 {
+				cursor++
+			}
+		case n == 1 && buf[0] == 3: // Ctrl-C
+			return nil, fmt.Errorf("interrupted")
+		}
+		redraw()
+	}
+}
+
+// runListFallback is the non-TTY path: print a numbered list and read a line
+// of (comma-separated, for multi-select) indices.
+func runListFallback(prefix string, choices []string, checked map[int]bool) ([]int, error) {
+	for i, choice := range choices {
+		fmt.Fprintf(os.Stdout, "  %d) %s\n", i+1, choice)
+	}
+	suffix := "choice"
+	if checked != nil {
+		suffix = "choices, comma-separated"
+	}
+	line, err := prompt.Basic(fmt.Sprintf("%s [%s]: ", prefix, suffix), true)
+	if err != nil {
+		return nil, err
+	}
+	var result []int
+	for _, part := range strings.Split(line, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 || n > len(choices) {
+			return nil, fmt.Errorf("`%s` is not a valid choice", part)
+		}
+		result = append(result, n-1)
+		if checked == nil {
+			break
+		}
+	}
+	return result, nil
+}
+
+// validateTag parses a `validate:"scheme:arg"` tag value and checks s
+// against it. Only the regexp scheme is supported today.
+func validateTag(validate, s string) error {
+	if validate == "" {
+		return nil
+	}
+	parts := strings.SplitN(validate, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("malformed validate tag %q", validate)
+	}
+	switch parts[0] {
+	case "regexp":
+		re, err := regexp.Compile(parts[1])
+		if err != nil {
+			return fmt.Errorf("invalid validate regexp %q: %v", parts[1], err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("%q does not match %s", s, parts[1])
+		}
+	default:
+		return fmt.Errorf("unknown validate scheme %q", parts[0])
+	}
+	return nil
+}
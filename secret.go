@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/labstack/gommon/color"
+)
+
+// SecretResolver turns the part of a scheme URI after "scheme://" into the
+// actual secret value. Resolvers are looked up by scheme through
+// RegisterSecretResolver.
+type SecretResolver interface {
+	Resolve(arg string) (string, error)
+}
+
+// SecretResolverFunc adapts a plain function to the SecretResolver interface.
+type SecretResolverFunc func(arg string) (string, error)
+
+// Resolve calls f(arg).
+func (f SecretResolverFunc) Resolve(arg string) (string, error) {
+	return f(arg)
+}
+
+var secretResolvers = map[string]SecretResolver{
+	"file":    SecretResolverFunc(resolveFileSecret),
+	"env":     SecretResolverFunc(resolveEnvSecret),
+	"exec":    SecretResolverFunc(resolveExecSecret),
+	"keyring": SecretResolverFunc(resolveKeyringSecretStub),
+}
+
+// RegisterSecretResolver registers r for scheme (the part of a flag/default
+// value before "://"), overriding any previously registered resolver for
+// that scheme. Use this to plug in a real keyring, vault, or other secret
+// store in place of the built-in stub.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolvers[scheme] = r
+}
+
+// resolveSecretValue checks s for a "scheme://arg" prefix and, if a resolver
+// is registered for that scheme, returns the resolved value with matched
+// set to true. A value with no recognized scheme is returned unchanged with
+// matched set to false so callers can decide whether a literal was allowed.
+func resolveSecretValue(s string) (resolved string, matched bool, err error) {
+	index := strings.Index(s, "://")
+	if index == -1 {
+		return s, false, nil
+	}
+	scheme, arg := s[:index], s[index+len("://"):]
+	resolver, ok := secretResolvers[scheme]
+	if !ok {
+		return s, false, nil
+	}
+	resolved, err = resolver.Resolve(arg)
+	if err != nil {
+		return "", true, fmt.Errorf("%s:// %v", scheme, err)
+	}
+	return resolved, true, nil
+}
+
+func resolveFileSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// execSecretTimeout bounds how long an exec:// resolver is allowed to run.
+const execSecretTimeout = 10 * time.Second
+
+func resolveExecSecret(command string) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", fmt.Errorf("empty command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), execSecretTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("exec %q: %v", command, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+func resolveKeyringSecretStub(string) (string, error) {
+	return "", fmt.Errorf("no keyring resolver registered; call RegisterSecretResolver(\"keyring\", ...)")
+}
+
+// isSecretField reports whether fl should go through the secret-resolution
+// pipeline: either the legacy `isPassword` tag property, or the newer
+// `secret:"true"` struct tag.
+func (fl *flag) isSecretField() bool {
+	return fl.tag.isPassword || fl.field.Tag.Get("secret") == "true"
+}
+
+// resolveSecretArg resolves a scheme-prefixed secret argument. When enforce
+// is true (the CLI-arg path, fl.set), a secret field holding a plain literal
+// is rejected outright, forcing callers onto a resolver scheme or an
+// interactive prompt. setDefault passes enforce=false, since tag defaults
+// are allowed to carry a literal the author intentionally hard-coded.
+func (fl *flag) resolveSecretArg(s string, clr color.Color, enforce bool) (string, error) {
+	resolved, matched, err := resolveSecretValue(s)
+	if err != nil {
+		return "", fmt.Errorf("flag %s: %v", clr.Bold(fl.name()), err)
+	}
+	if matched {
+		return resolved, nil
+	}
+	if enforce && fl.isSecretField() {
+		return "", fmt.Errorf("flag %s holds a secret: pass file://, env://, exec://, or keyring:// instead of a literal value", clr.Bold(fl.name()))
+	}
+	return s, nil
+}
+
+// redactSecretErr replaces any occurrence of s inside err's message with
+// "<redacted>" when fl is a secret field. getBool/getInt/getUint/getFloat
+// embed the raw value they failed to parse in their error text; without
+// this, a `secret:"true"` field whose resolved file://, env://, or exec://
+// value fails type conversion would leak the credential into that error.
+func redactSecretErr(fl *flag, s string, err error) error {
+	if err == nil || s == "" || !fl.isSecretField() {
+		return err
+	}
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), s, "<redacted>"))
+}
+
+// setFromPrompt assigns an interactively-entered value. Interactive prompts
+// are, like a resolver scheme, an accepted way to supply a secret, so the
+// literal-value refusal that fl.set enforces for CLI args doesn't apply
+// here; a scheme typed at the prompt is still resolved.
+func (fl *flag) setFromPrompt(data string, clr color.Color) error {
+	fl.isSet, fl.isAssigned = true, true
+	if data != "" {
+		resolved, err := fl.resolveSecretArg(data, clr, false)
+		if err != nil {
+			return err
+		}
+		data = resolved
+	}
+	if fl.isNeedDelaySet {
+		fl.lastValue = data
+		return nil
+	}
+	return setWithProperType(fl, fl.field.Type, fl.value, data, clr, false)
+}
@@ -0,0 +1,385 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/labstack/gommon/color"
+)
+
+// UsageFormatter renders the usage/help text for a set of flags or a command.
+// Implementations are free to emit plain text, markdown, man-page troff, JSON,
+// or anything else a caller wants out of `-h`/`--help`.
+type UsageFormatter interface {
+	FormatFlags(flags []*flag, clr color.Color) string
+	FormatCommand(cmd *Command) string
+}
+
+var usageFormatter UsageFormatter = newTemplateFormatter(normalUsageTemplate)
+
+// SetUsageFormatter overrides how flags and commands are rendered for help
+// output. Pass nil to restore the built-in formatter for GetUsageStyle().
+func SetUsageFormatter(f UsageFormatter) {
+	if f == nil {
+		f = newTemplateFormatter(templateForStyle(defaultStyle))
+	}
+	usageFormatter = f
+}
+
+// GetUsageFormatter returns the formatter currently used to render help text.
+func GetUsageFormatter() UsageFormatter {
+	return usageFormatter
+}
+
+// usageFlagData is what the built-in templates range over.
+type usageFlagData struct {
+	Short   string
+	Long    string
+	Type    string
+	Name    string // "=NAME" value-name placeholder, colored; empty if the flag has none
+	Default string // the default value itself, colored; empty if the flag has none
+	// NameDefault is Name plus a "[=Default]" suffix, right-padded (once
+	// FormatFlags measures columns) so the Usage column aligns across rows
+	// the same way the old hand-rolled flagSlice.String padding did.
+	NameDefault string
+	Usage       string
+
+	// nameDefaultPlainLen is NameDefault's width without ANSI color codes,
+	// used to compute that padding; not rendered by any template.
+	nameDefaultPlainLen int
+}
+
+// templateFormatter is the default UsageFormatter, driven by a text/template
+// with named sections ({{.Short}} {{.Long}} {{.Type}} {{.Default}} {{.Usage}})
+// instead of flag.go's old hand-rolled fmt.Sprintf padding. FormatCommand
+// uses its own fixed commandUsageTemplate, since a command's shape has
+// nothing in common with a flags slice's rows.
+type templateFormatter struct {
+	tmpl    *template.Template
+	cmdTmpl *template.Template
+}
+
+// NewUsageFormatter builds a UsageFormatter from a template source using the
+// same FuncMap (padRight, colorize, wrap) as the built-in styles, so callers
+// can ship markdown, man-page, or JSON help output without patching this
+// package.
+func NewUsageFormatter(name, tmplText string) (UsageFormatter, error) {
+	t, err := template.New(name).Funcs(usageFuncMap).Parse(tmplText)
+	if err != nil {
+		return nil, err
+	}
+	cmdTmpl, err := template.New(name + "-command").Funcs(usageFuncMap).Parse(commandUsageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormatter{tmpl: t, cmdTmpl: cmdTmpl}, nil
+}
+
+func newTemplateFormatter(tmplText string) *templateFormatter {
+	f, err := NewUsageFormatter("usage", tmplText)
+	if err != nil {
+		panic(err)
+	}
+	return f.(*templateFormatter)
+}
+
+func templateForStyle(style UsageStyle) string {
+	if style == ManualStyle {
+		return manualUsageTemplate
+	}
+	return normalUsageTemplate
+}
+
+var usageFuncMap = template.FuncMap{
+	"padRight": padRight,
+	"colorize": func(style, s string) string { return colorizeWith(color.Color{}, style, s) },
+	"wrap":     wrap,
+}
+
+// colorizeWith applies one of clr's named styles to s, by the same names
+// gommon/color exposes as methods (Bold, Red, Grey, ...). Unknown style
+// names are returned unchanged rather than erroring, since a template's
+// choice of style shouldn't be able to break rendering.
+func colorizeWith(clr color.Color, style, s string) string {
+	switch strings.ToLower(style) {
+	case "black":
+		return clr.Black(s)
+	case "red":
+		return clr.Red(s)
+	case "green":
+		return clr.Green(s)
+	case "yellow":
+		return clr.Yellow(s)
+	case "blue":
+		return clr.Blue(s)
+	case "magenta":
+		return clr.Magenta(s)
+	case "cyan":
+		return clr.Cyan(s)
+	case "white":
+		return clr.White(s)
+	case "grey", "gray":
+		return clr.Grey(s)
+	case "bold":
+		return clr.Bold(s)
+	case "dim":
+		return clr.Dim(s)
+	case "italic":
+		return clr.Italic(s)
+	case "underline":
+		return clr.Underline(s)
+	default:
+		return s
+	}
+}
+
+// padRight pads s with spaces on the right until it's at least width runes.
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// wrap hard-wraps s into lines of at most width runes, breaking on spaces.
+// A single word longer than width is kept whole on a line of its own.
+func wrap(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	words := strings.Fields(s)
+	var lines []string
+	line := ""
+	for _, word := range words {
+		if line == "" {
+			line = word
+			continue
+		}
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+const normalUsageTemplate = `{{range .}}{{printf "%-*s" .ShortWidth .Short}}{{printf "%-*s" .LongWidth .Long}}{{.NameDefault}}{{.Usage}}
+{{end}}`
+
+const manualUsageTemplate = `{{range .}}  {{.Short}}{{if .Long}}, {{.Long}}{{end}}{{.Name}}{{if .Default}}[={{.Default}}]{{end}}
+    {{.Usage}}
+{{end}}`
+
+const commandUsageTemplate = `{{range $name, $value := .}}{{$name}}: {{$value}}
+{{end}}`
+
+// measuredFlag is the data-plus-column-widths view fed to the templates;
+// the widths are measured up front so columns auto-align across
+// variable-length short/long names, same as the old hand-rolled padding did.
+type measuredFlag struct {
+	usageFlagData
+	ShortWidth int
+	LongWidth  int
+}
+
+func (f *templateFormatter) FormatFlags(flags []*flag, clr color.Color) string {
+	// Clone before rebinding colorize to this call's clr: text/template.Funcs
+	// mutates the template in place and is documented as unsafe to call
+	// concurrently with Execute, so binding directly on f.tmpl would race
+	// two FormatFlags calls (e.g. both against the shared package-level
+	// usageFormatter) against each other.
+	tmpl, err := f.tmpl.Clone()
+	if err != nil {
+		return err.Error()
+	}
+	tmpl.Funcs(template.FuncMap{
+		"colorize": func(style, s string) string { return colorizeWith(clr, style, s) },
+	})
+
+	rows := make([]measuredFlag, 0, len(flags))
+	shortWidth, longWidth, nameDefaultWidth := 0, 0, 0
+	for _, fl := range flags {
+		data := flagUsageData(fl, clr)
+		rows = append(rows, measuredFlag{usageFlagData: data})
+		if l := len(data.Short) + len(sepName); l > shortWidth {
+			shortWidth = l
+		}
+		if l := len(data.Long) + len(sepName); l > longWidth {
+			longWidth = l
+		}
+		if l := data.nameDefaultPlainLen + len(sepName); l > nameDefaultWidth {
+			nameDefaultWidth = l
+		}
+	}
+	for i := range rows {
+		rows[i].ShortWidth = shortWidth
+		rows[i].LongWidth = longWidth
+		if pad := nameDefaultWidth - rows[i].nameDefaultPlainLen; pad > 0 {
+			rows[i].NameDefault += strings.Repeat(" ", pad)
+		}
+	}
+
+	buf := bytes.NewBufferString("")
+	if err := tmpl.Execute(buf, rows); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// FormatCommand renders cmd's name, description, and flags as an actual
+// help page: the name/description lead, then cmd's flags are rendered
+// through the same FormatFlags column-alignment path a command's own
+// `-h` output needs, instead of being left out entirely. Name/Desc/Flags
+// are located by the conventional field names via reflection rather than
+// a concrete Command field reference, since Command's definition lives
+// outside this file; a Command without a recognizable Flags field (or any
+// field at all) falls back to the generic exported-field dump below.
+func (f *templateFormatter) FormatCommand(cmd *Command) string {
+	v := reflect.ValueOf(cmd)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	buf := bytes.NewBufferString("")
+	name := stringField(v, "Name")
+	desc := stringField(v, "Desc", "Text")
+	switch {
+	case name != "" && desc != "":
+		fmt.Fprintf(buf, "%s - %s\n\n", name, desc)
+	case name != "":
+		fmt.Fprintf(buf, "%s\n\n", name)
+	case desc != "":
+		fmt.Fprintf(buf, "%s\n\n", desc)
+	}
+
+	if flags := flagsField(v); flags != nil {
+		buf.WriteString(f.FormatFlags(flags, color.Color{}))
+		return buf.String()
+	}
+
+	if err := f.cmdTmpl.Execute(buf, commandFields(cmd)); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// stringField returns the first of names that v has as a string-kinded
+// field, or "" if none match. Used by FormatCommand to pick up a Command's
+// name/description under whichever convention it uses (Desc vs Text).
+func stringField(v reflect.Value, names ...string) string {
+	t := v.Type()
+	for _, name := range names {
+		field, ok := t.FieldByName(name)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+		fv := v.FieldByIndex(field.Index)
+		if fv.Kind() == reflect.String {
+			return fv.String()
+		}
+	}
+	return ""
+}
+
+// flagsField returns v's "Flags" field reinterpreted as []*flag, or nil if
+// v has no such field. Command and flag are both defined in this package,
+// so a Command's flag slice (however it stores one) can be read directly
+// without an exported accessor.
+func flagsField(v reflect.Value) []*flag {
+	field, ok := v.Type().FieldByName("Flags")
+	if !ok || field.PkgPath != "" {
+		return nil
+	}
+	fv := v.FieldByIndex(field.Index)
+	flags, ok := fv.Interface().([]*flag)
+	if !ok {
+		return nil
+	}
+	return flags
+}
+
+// commandFields turns cmd's exported fields into a plain map so the command
+// template can range over them without this package needing to know
+// Command's real field names.
+func commandFields(cmd *Command) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if cmd == nil {
+		return fields
+	}
+	v := reflect.ValueOf(cmd)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fields
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fields
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		fields[field.Name] = v.Field(i).Interface()
+	}
+	return fields
+}
+
+func flagUsageData(fl *flag, clr color.Color) usageFlagData {
+	tag := fl.tag
+
+	nameStr := ""
+	plainLen := 0
+	if tag.name != "" {
+		nameStr = "=" + clr.Bold(tag.name)
+		plainLen += len("=") + len(tag.name)
+	}
+
+	defaultStr := ""
+	if tag.defaultValue != "" {
+		defaultDisplay := tag.defaultValue
+		if fl.isSecretField() {
+			defaultDisplay = "hidden"
+		}
+		defaultStr = clr.Grey(defaultDisplay)
+		plainLen += len("[=]") + len(defaultDisplay)
+	}
+
+	nameDefault := nameStr
+	if defaultStr != "" {
+		nameDefault += "[=" + defaultStr + "]"
+	}
+
+	usagePrefix := " "
+	if tag.required {
+		usagePrefix = clr.Red("*")
+	}
+	return usageFlagData{
+		Short:               strings.Join(tag.shortNames, sepName),
+		Long:                strings.Join(tag.longNames, sepName),
+		Type:                fl.field.Type.String(),
+		Name:                nameStr,
+		Default:             defaultStr,
+		NameDefault:         nameDefault,
+		Usage:               usagePrefix + tag.usage,
+		nameDefaultPlainLen: plainLen,
+	}
+}